@@ -21,403 +21,536 @@
 package main
 
 import (
-	"bytes"
+	"context"
 	"crypto/tls"
-	"fmt"
-	"math/rand"
+	"flag"
+	"log/slog"
 	"net/http"
+	"os"
+	"os/signal"
 	"strconv"
+	"strings"
+	"syscall"
 	"time"
+
+	"github.com/osisoft/OMF-Samples/go/af"
+	"github.com/osisoft/OMF-Samples/go/endpoint"
+	"github.com/osisoft/OMF-Samples/go/metrics"
+	"github.com/osisoft/OMF-Samples/go/naming"
+	"github.com/osisoft/OMF-Samples/go/omf"
+	"github.com/osisoft/OMF-Samples/go/queue"
+	"github.com/osisoft/OMF-Samples/go/sensor"
+	"github.com/osisoft/OMF-Samples/go/sensor/gpio"
+	"github.com/osisoft/OMF-Samples/go/sensor/mqtt"
+	"github.com/osisoft/OMF-Samples/go/sensor/ngsi"
 )
 
 // ************************************************************************
-// Specify constant values (names, target URLS, etc.) needed by the script
+// Every flag below also reads its default from an OMF_-prefixed environment
+// variable of the same name (e.g. --device-name / OMF_DEVICE_NAME), so this
+// can be configured the same way whether it's run by hand or under a
+// process supervisor that only sets environment variables.
 // ************************************************************************
 
-// DEVICE_NAME ... Specify the name of this device, or simply use the hostname this is the name
-// of the PI AF Element that will be created, and it'll be included in the names
-// of PI Points that get created as well
-const DEVICE_NAME = "OMF Data Source (Go)"
+// envDefault returns the value of the environment variable key, or def if
+// it's unset.
+func envDefault(key, def string) string {
+	if v, ok := os.LookupEnv(key); ok {
+		return v
+	}
+	return def
+}
 
-// DEVICE_LOCATION ... Specify a device location (optional) this will be added as a static
-// string attribute to the AF Element that is created
-const DEVICE_LOCATION = "IoT Test Lab"
+// envDefaultBool is envDefault for a flag.Bool default.
+func envDefaultBool(key string, def bool) bool {
+	v, ok := os.LookupEnv(key)
+	if !ok {
+		return def
+	}
+	b, err := strconv.ParseBool(v)
+	if err != nil {
+		return def
+	}
+	return b
+}
 
-// ASSETS_MESSAGE_TYPE_NAME ... Specify the name of the Assets type message this will also end up becoming
-// part of the name of the PI AF Element template that is created for example, this could be
-// "AssetsType_RaspberryPI" or "AssetsType_Dragonboard"
-// You will want to make this different for each general class of IoT module that you use
-const ASSETS_MESSAGE_TYPE_NAME = DEVICE_NAME + "_assets_type" + ""
+// envDefaultInt is envDefault for a flag.Int default.
+func envDefaultInt(key string, def int) int {
+	v, ok := os.LookupEnv(key)
+	if !ok {
+		return def
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil {
+		return def
+	}
+	return n
+}
 
-//ASSETS_MESSAGE_TYPE_NAME := "assets_type" + "IoT Device Model 74656" // An example
+// envDefaultDuration is envDefault for a flag.Duration default.
+func envDefaultDuration(key string, def time.Duration) time.Duration {
+	v, ok := os.LookupEnv(key)
+	if !ok {
+		return def
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil {
+		return def
+	}
+	return d
+}
 
-// DATA_VALUES_MESSAGE_TYPE_NAME ... Similarly, specify the name of for the data values type this should likewise be unique
-// for each general class of IoT device--for example, if you were running this
-// script on two different devices, each with different numbers and kinds of sensors,
-// you'd specify a different data values message type name
-// when running the script on each device.  If both devices were the same,
-// you could use the same DATA_VALUES_MESSAGE_TYPE_NAME
-const DATA_VALUES_MESSAGE_TYPE_NAME = "data_values_type" + ""
+// ************************************************************************
+// Command-line flags: identify this device and how often it reports.
+// ************************************************************************
 
-//DATA_VALUES_MESSAGE_TYPE_NAME := "data_values_type" + "IoT Device Model 74656" // An example
+var (
+	flagDeviceName = flag.String("device-name", envDefault("OMF_DEVICE_NAME", "OMF Data Source (Go)"),
+		"name of this device; becomes the name of the PI AF Element that gets created, and is included in the names of the PI Points that get created as well")
+	flagDeviceLocation = flag.String("device-location", envDefault("OMF_DEVICE_LOCATION", "IoT Test Lab"),
+		"device location; added as a static string attribute to the AF Element that is created")
+	flagInterval = flag.Duration("interval", envDefaultDuration("OMF_INTERVAL", 2*time.Second),
+		"how often to send a data values message")
+	flagLogLevel = flag.String("log-level", envDefault("OMF_LOG_LEVEL", "info"),
+		`log level: "debug" (also logs every OMF request/response body), "info", "warn", or "error"`)
+)
 
-// DATA_VALUES_CONTAINER_ID ... Store the id of the container that will be used to receive live data values
-const DATA_VALUES_CONTAINER_ID = (DEVICE_NAME + "_data_values_container")
+// ************************************************************************
+// Command-line flags: pick which kind of OMF endpoint to target and how
+// to reach it. See the endpoint package for what each kind expects.
+// ************************************************************************
 
-// NUMBER_OF_SECONDS_BETWEEN_VALUE_MESSAGES ... Specify the number of seconds to sleep in between value messages
-const NUMBER_OF_SECONDS_BETWEEN_VALUE_MESSAGES = 2
+var (
+	flagEndpointKind = flag.String("endpoint", envDefault("OMF_ENDPOINT", string(endpoint.KindRelay)),
+		`which kind of OMF endpoint to target: "relay", "webapi", "eds", or "ocs"`)
+	flagURL = flag.String("url", envDefault("OMF_URL", "https://lopezpiserver:777"),
+		"base URL of the target endpoint, without the /omf or /ingress/messages suffix")
+	flagProducerToken = flag.String("producer-token", envDefault("OMF_PRODUCER_TOKEN", "OMFv1"),
+		`producer token; only used when --endpoint="relay"`)
+	flagUsername = flag.String("username", envDefault("OMF_USERNAME", ""),
+		`PI Web API username; only used when --endpoint="webapi"`)
+	flagPassword = flag.String("password", envDefault("OMF_PASSWORD", ""),
+		`PI Web API password; only used when --endpoint="webapi"`)
+	flagClientID = flag.String("client-id", envDefault("OMF_CLIENT_ID", ""),
+		`OCS/ADH OAuth client id; only used when --endpoint="ocs"`)
+	flagClientSecret = flag.String("client-secret", envDefault("OMF_CLIENT_SECRET", ""),
+		`OCS/ADH OAuth client secret; only used when --endpoint="ocs"`)
+	flagTokenURL = flag.String("token-url", envDefault("OMF_TOKEN_URL", "https://login.osisoft.com/oauth2/token"),
+		`OCS/ADH OAuth token URL; only used when --endpoint="ocs"`)
+	flagInsecureSkipVerify = flag.Bool("insecure-skip-verify", envDefaultBool("OMF_INSECURE_SKIP_VERIFY", true),
+		"skip TLS certificate verification (useful for self-signed PI Web API/EDS installs)")
+)
 
-// SEND_DATA_TO_OSISOFT_CLOUD_SERVICES ... Specify whether you're sending data to OSIsoft cloud services or not
-const SEND_DATA_TO_OSISOFT_CLOUD_SERVICES = false
+// ************************************************************************
+// Command-line flags: how PI points are named and where the AF Element
+// lands. See the naming and af packages for the template syntax and
+// hierarchy semantics.
+// ************************************************************************
 
-// TARGET_URL ... Specify the address of the destination endpoint it should be of the form
-// http://<host/ip>:<port>/ingress/messages
-// For example, "https://myservername:8118/ingress/messages"
-const TARGET_URL = "https://lopezpiserver:777/ingress/messages"
+var (
+	flagNamingScheme = flag.String("naming-scheme", envDefault("OMF_NAMING_SCHEME", "{device}.{measurement}"),
+		`template for the data values container name; supports the shorthand tokens "{device}" and "{measurement}", or a full Go text/template expression`)
+	flagAFPath = flag.String("af-path", envDefault("OMF_AF_PATH", ""),
+		`AF path like "Site/Building/Room" to place the Element under, auto-creating parent Elements as needed; empty places it directly under _ROOT`)
+	flagSendFullStructure = flag.Bool("send-full-structure", envDefaultBool("OMF_SEND_FULL_STRUCTURE", true),
+		"send Type, static Asset, and __Link messages; set false to send only the Container and Data messages against AF structure that already exists")
+)
 
-// !!! Note: if sending data to OSIsoft cloud services,
-// uncomment the below line in order to set the target URL to the OCS OMF endpoint:
-//TARGET_URL := "https://dat-a.osisoft.com/api/omf"
+// ************************************************************************
+// Command-line flags: the store-and-forward queue that sits in front of
+// the endpoint, so readings collected while it's unreachable are kept and
+// replayed once it comes back. See the queue package for the retry and
+// backpressure behavior.
+// ************************************************************************
 
-// PRODUCER_TOKEN ... Specify the producer token, a unique token used to identify and authorize a given OMF producer. Consult the OSIsoft Cloud Services or PI Connector Relay documentation for further information.
-const PRODUCER_TOKEN = "OMFv1"
+var (
+	flagQueuePath = flag.String("queue-path", envDefault("OMF_QUEUE_PATH", "omf-queue.db"),
+		"path to the BoltDB file used to buffer data values while the endpoint is unreachable")
+	flagQueueMaxDepth = flag.Int("queue-max-depth", envDefaultInt("OMF_QUEUE_MAX_DEPTH", 10000),
+		"maximum number of buffered data values before the oldest are dropped to make room; 0 means unbounded")
+	flagQueueBatchSize = flag.Int("queue-batch-size", envDefaultInt("OMF_QUEUE_BATCH_SIZE", 100),
+		"maximum number of data values sent in a single OMF Data message when draining the queue")
+	flagQueueDrainInterval = flag.Duration("queue-drain-interval", envDefaultDuration("OMF_QUEUE_DRAIN_INTERVAL", time.Second),
+		"how often the queue is polled for messages to send")
+	flagMetricsAddr = flag.String("metrics-addr", envDefault("OMF_METRICS_ADDR", ":2112"),
+		`address to serve Prometheus metrics on at "/metrics"; empty disables it`)
+)
 
-//PRODUCER_TOKEN := "778408" // An example
-// !!! Note: if sending data to OSIsoft cloud services, the producer token should be the
-// security token obtained for a particular Tenant and Publisher see
-// http://qi-docs.readthedocs.io/en/latest/OMF_Ingress_Specification.html//headers
-//PRODUCER_TOKEN := ""
+// ************************************************************************
+// Command-line flags: pick which sensor sources feed live readings. See
+// the sensor package and its gpio, mqtt, and ngsi subpackages for what
+// each source reports.
+// ************************************************************************
+
+var (
+	flagSensorSources = flag.String("sensor-sources", envDefault("OMF_SENSOR_SOURCES", "simulated"),
+		`comma-separated sources to read from: "simulated", "gpio", "mqtt", "ngsi"`)
+	flagGPIOPins = flag.String("gpio-pins", envDefault("OMF_GPIO_PINS", "GPIO4,GPIO5"),
+		`comma-separated periph.io pin names to read as digital inputs; only used when --sensor-sources includes "gpio"`)
+	flagMQTTBroker = flag.String("mqtt-broker", envDefault("OMF_MQTT_BROKER", "tcp://localhost:1883"),
+		`MQTT broker URL; only used when --sensor-sources includes "mqtt"`)
+	flagMQTTTopicPattern = flag.String("mqtt-topic-pattern", envDefault("OMF_MQTT_TOPIC_PATTERN", "+/+/temperature"),
+		`MQTT topic filter to subscribe to; only used when --sensor-sources includes "mqtt"`)
+	flagMQTTFields = flag.String("mqtt-fields", envDefault("OMF_MQTT_FIELDS", "temperature"),
+		`comma-separated field names expected as the final segment of a matching topic (e.g. ".../temperature"); declared up front so the dynamic Type sent at startup matches whatever values arrive later; only used when --sensor-sources includes "mqtt"`)
+	flagNGSIBrokerURL = flag.String("ngsi-broker-url", envDefault("OMF_NGSI_BROKER_URL", "http://localhost:1026"),
+		`FIWARE Context Broker URL; only used when --sensor-sources includes "ngsi"`)
+	flagNGSIEntityID = flag.String("ngsi-entity-id", envDefault("OMF_NGSI_ENTITY_ID", ""),
+		`NGSIv2 entity id to poll; only used when --sensor-sources includes "ngsi"`)
+	flagNGSIAttributes = flag.String("ngsi-attributes", envDefault("OMF_NGSI_ATTRIBUTES", "temperature"),
+		`comma-separated NGSIv2 entity attributes to poll; only used when --sensor-sources includes "ngsi"`)
+)
 
 // ************************************************************************
 // Specify options for sending web requests to the target
 // ************************************************************************
-// Set up the http transport configuration
-var tr = &http.Transport{
-	TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+// The http transport configuration is finalized in main(), once flags
+// (in particular --insecure-skip-verify) have been parsed.
+var tr = &http.Transport{}
+
+// fatal logs msg at Error level, with args as structured key/value pairs,
+// then exits with a non-zero status.
+func fatal(logger *slog.Logger, msg string, args ...any) {
+	logger.Error(msg, args...)
+	os.Exit(1)
 }
 
 // ************************************************************************
-// Helper function: run any code needed to initialize local sensors, if necessary for this hardware
+// Helper function: build the sensor.Registry requested via --sensor-sources
 // ************************************************************************
 
-// Below is where you can initialize any global variables that are needed by your application
-// certain sensors, for example, will require global interface or sensor variables
-// myExampleInterfaceKitGlobalVar := None
-
-// The following function is where you can insert specific initialization code to set up
-// sensors for a particular IoT module or platform
-func initializeSensors() {
-	fmt.Println("\n--- Sensors initializing...")
-	//For a raspberry pi, for example, to set up pins 4 and 5, you would add
-	//GPIO.setmode(GPIO.BCM)
-	//GPIO.setup(4, GPIO.IN)
-	//GPIO.setup(5, GPIO.IN)
-	fmt.Println("--- Sensors initialized!")
-	// In short, in this example, by default,
-	// this function is called but doesn't do anything (it's just a placeholder)
+// registerSources builds the set of sensor.Sources this run should read
+// from, as selected by --sensor-sources. By default that's just the
+// built-in Simulated source, so the sample still runs with no hardware or
+// brokers attached; --sensor-sources=gpio,mqtt,ngsi (in any combination)
+// wires up a real edge gateway instead.
+func registerSources(logger *slog.Logger) *sensor.Registry {
+	var sources []sensor.Source
+	for _, name := range strings.Split(*flagSensorSources, ",") {
+		switch strings.TrimSpace(name) {
+		case "simulated":
+			sources = append(sources, sensor.Simulated{})
+		case "gpio":
+			src, err := gpio.New(strings.Split(*flagGPIOPins, ",")...)
+			if err != nil {
+				fatal(logger, "registering gpio source", "error", err)
+			}
+			sources = append(sources, src)
+		case "mqtt":
+			src, err := mqtt.New(*flagMQTTBroker, *flagMQTTTopicPattern, strings.Split(*flagMQTTFields, ","))
+			if err != nil {
+				fatal(logger, "registering mqtt source", "error", err)
+			}
+			sources = append(sources, src)
+		case "ngsi":
+			sources = append(sources, ngsi.New(*flagNGSIBrokerURL, *flagNGSIEntityID, strings.Split(*flagNGSIAttributes, ",")))
+		default:
+			fatal(logger, "unknown --sensor-sources entry", "entry", name)
+		}
+	}
+	return sensor.NewRegistry(sources...)
 }
 
 // ************************************************************************
 // Helper function: REQUIRED: create a JSON message that contains sensor data values
 // ************************************************************************
 
-// The following function you can customize to allow this script to send along any
-// number of different data values, so long as the values that you send here match
-// up with the values defined in the "DataValuesType" OMF message type (see the next section)
-// In this example, this function simply generates two random values for the sensor values,
-// but here is where you could change this function to reference a library that actually
-// reads from sensors attached to the device that's running the script
-
-func createDataValuesMessage() string {
+// createDataValuesMessage reads the latest values from every registered
+// sensor.Source and assembles them into the Data message the
+// "DataValuesType" container expects.
+func createDataValuesMessage(ctx context.Context, logger *slog.Logger, sources *sensor.Registry, containerID string) omf.DataMessage {
 	// Get the current timestamp in ISO format
 	timestamp := time.Now().UTC().Format("2006-01-02T15:04:05Z")
-	// Assemble a JSON object containing the streamId and any data values
-	return ("[" +
-		"{" +
-		"\"containerid\": \"" + DATA_VALUES_CONTAINER_ID + "\"," +
-		"\"values\": [" +
-		"{" +
-		"\"Time\": \"" + timestamp + "\"," +
-		// Again, in this example,
-		// we're just sending along random values for these two \"sensors\"
-		"\"Raw Sensor Reading 1\":" + strconv.FormatFloat(100*rand.Float64(), 'f', -1, 64) + "," +
-		"\"Raw Sensor Reading 2\":" + strconv.FormatFloat(100*rand.Float64(), 'f', -1, 64) + "" +
-		// If you wanted to read, for example, the digital GPIO pins
-		// 4 and 5 on a Raspberry PI,
-		// you would add to the earlier package import section:
-		// import RPi.GPIO as GPIO
-		// then add the below 3 lines to the above initializeSensors
-		// function to set up the GPIO pins:
-		// GPIO.setmode(GPIO.BCM)
-		// GPIO.setup(4, GPIO.IN)
-		// GPIO.setup(5, GPIO.IN)
-		// and then lastly, you would change the two Raw Sensor reading lines above to
-		// \"Raw Sensor Reading 1\": GPIO.input(4),
-		// \"Raw Sensor Reading 2\": GPIO.input(5)
-		"}" +
-		"]" +
-		"}" +
-		"]")
+
+	readings, errs := sources.Read(ctx)
+	for _, err := range errs {
+		logger.Warn("reading sensor source", "error", err)
+	}
+	readings["Time"] = timestamp
+
+	return omf.DataMessage{
+		ContainerID: containerID,
+		Values:      []map[string]any{readings},
+	}
 }
 
-// ************************************************************************
-// Helper function: REQUIRED: wrapper function for sending an HTTPS message
-// ************************************************************************
+func main() {
+	flag.Parse()
+
+	var logLevel slog.Level
+	if err := logLevel.UnmarshalText([]byte(*flagLogLevel)); err != nil {
+		logLevel = slog.LevelInfo
+	}
+	logger := slog.New(slog.NewJSONHandler(os.Stderr, &slog.HandlerOptions{Level: logLevel}))
+	slog.SetDefault(logger)
+
+	// time.NewTicker panics on a non-positive duration, so a bad
+	// --interval/--queue-drain-interval (or OMF_INTERVAL/
+	// OMF_QUEUE_DRAIN_INTERVAL) needs to fail here with a clear message
+	// instead of taking down the whole process later.
+	if *flagInterval <= 0 {
+		fatal(logger, "invalid --interval: must be positive", "interval", flagInterval.String())
+	}
+	if *flagQueueDrainInterval <= 0 {
+		fatal(logger, "invalid --queue-drain-interval: must be positive", "queue-drain-interval", flagQueueDrainInterval.String())
+	}
 
-// Define a helper function to allow easily sending web request messages
-// this function can later be customized to allow you to port this script to other languages.
-// All it does is take in a data object and a message type, and it sends an HTTPS
-// request to the target OMF endpoint
-func sendOmfMessageToEndpoint(action, messageType, messageJSON string) {
-	// Create a connection object
-	client := &http.Client{Transport: tr}
-	req, err := http.NewRequest("POST", TARGET_URL, bytes.NewBuffer([]byte(messageJSON)))
-
-	// Assemble headers that contain the producer token and message type
-	// Note: in this example, the only action that is used is \"create\",
-	// which will work totally fine
-	// to expand this application, you could modify it to use the \"update\"
-	// action to, for example, modify existing AF element template types
-	req.Header.Add("producertoken", PRODUCER_TOKEN)
-	req.Header.Add("messagetype", messageType)
-	req.Header.Add("action", action)
-	req.Header.Add("messageformat", "JSON")
-	req.Header.Add("omfversion", "1.0")
-
-	// !!! Note: if desired, uncomment the below line to System.out.println the outgoing message
-	fmt.Println("\nOutgoing message: " + messageJSON)
-	// Send the request, and collect the response
-	resp, err := client.Do(req)
-
-	// Log any error, if it occurs
+	tr.TLSClientConfig = &tls.Config{InsecureSkipVerify: *flagInsecureSkipVerify}
+
+	// signal.NotifyContext cancels ctx on SIGINT/SIGTERM instead of killing
+	// the process outright, so the loop below gets a chance to stop
+	// enqueuing new readings and the deferred q.Close() flushes the queue
+	// file to disk before the process exits.
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	ep, err := endpoint.New(endpoint.Config{
+		Kind:               endpoint.Kind(*flagEndpointKind),
+		BaseURL:            *flagURL,
+		ProducerToken:      *flagProducerToken,
+		Username:           *flagUsername,
+		Password:           *flagPassword,
+		ClientID:           *flagClientID,
+		ClientSecret:       *flagClientSecret,
+		TokenURL:           *flagTokenURL,
+		InsecureSkipVerify: *flagInsecureSkipVerify,
+	})
 	if err != nil {
-		fmt.Println((time.Now().UTC().Format("2006-01-02T15:04:05Z")) + " Error during web request: ")
-		fmt.Println(err)
+		fatal(logger, "configuring endpoint", "endpoint", *flagEndpointKind, "error", err)
 	}
-	defer resp.Body.Close()
-	fmt.Println("Response code: ", resp.Status)
 
-}
+	omfVersion, err := endpoint.NegotiateVersion(ctx, ep, &http.Client{Transport: tr})
+	if err != nil {
+		fatal(logger, "negotiating OMF version", "endpoint", ep.Kind(), "error", err)
+	}
+	client := omf.NewClient(ep, &http.Client{Transport: tr}, omfVersion)
+	client.Logger = logger
+
+	q, err := queue.Open(*flagQueuePath,
+		queue.WithMaxDepth(*flagQueueMaxDepth, func(dropped omf.DataMessage) {
+			metrics.DroppedTotal.WithLabelValues(metrics.DroppedReasonOverflow).Inc()
+		}),
+		queue.WithDepthGauge(func(depth int) {
+			metrics.QueueDepth.Set(float64(depth))
+		}),
+	)
+	if err != nil {
+		fatal(logger, "opening queue", "path", *flagQueuePath, "error", err)
+	}
+	defer q.Close()
 
-func main() {
-	fmt.Println(
-		"\n--- Setup: targeting endpoint \"" + TARGET_URL + "\"..." +
-			"\n--- Now sending types, defining containers, and creating assets and links..." +
-			"\n--- (Note: a successful message will return a 20X response code.)\n")
+	sender := queue.NewSender(q, client, *flagQueueBatchSize)
+	sender.OnDepthChange = func(depth int) { metrics.QueueDepth.Set(float64(depth)) }
+	sender.OnDrop = func(dropped []omf.DataMessage) {
+		metrics.DroppedTotal.WithLabelValues(metrics.DroppedReasonRejected).Add(float64(len(dropped)))
+		logger.Warn("dropping permanently rejected data values", "count", len(dropped))
+	}
+	go sender.Run(ctx, *flagQueueDrainInterval)
+
+	if *flagMetricsAddr != "" {
+		metricsServer := &http.Server{Addr: *flagMetricsAddr, Handler: metrics.Handler()}
+		go func() {
+			if err := metricsServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+				logger.Error("metrics server stopped", "addr", *flagMetricsAddr, "error", err)
+			}
+		}()
+		go func() {
+			<-ctx.Done()
+			shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			metricsServer.Shutdown(shutdownCtx)
+		}()
+	}
+
+	// OCS/ADH and Edge Data Store have no Asset Framework of their own, so
+	// static Asset/Link messages don't apply there; --send-full-structure
+	// additionally lets a user targeting PI Web API or the Relay against
+	// already-built AF structure skip them too.
+	sendFullStructure := *flagSendFullStructure && !ep.SuppressStaticMessages()
+
+	namingScheme, err := naming.New(*flagNamingScheme)
+	if err != nil {
+		fatal(logger, "parsing naming scheme", "naming-scheme", *flagNamingScheme, "error", err)
+	}
+	dataValuesContainerID, err := namingScheme.Name(naming.Fields{Device: *flagDeviceName, Measurement: "data_values"})
+	if err != nil {
+		fatal(logger, "rendering naming scheme", "naming-scheme", *flagNamingScheme, "error", err)
+	}
+
+	// assetsMessageTypeName becomes part of the name of the PI AF
+	// Element template that is automatically created; make this different
+	// for each general class of IoT device you run this against.
+	assetsMessageTypeName := *flagDeviceName + "_assets_type"
+	// dataValuesMessageTypeName should likewise be unique for each
+	// general class of IoT device; devices of the same class can share it.
+	const dataValuesMessageTypeName = "data_values_type"
+
+	placement := af.New(assetsMessageTypeName, *flagAFPath)
+
+	logger.Info("setup", "endpoint", ep.MessageURL(), "omf_version", omfVersion)
 
 	// ************************************************************************
-	// Create a JSON packet to define the types of streams that will be sent
+	// Define the types of streams that will be sent
 	// ************************************************************************
 
-	DYNAMIC_TYPES_MESSAGE_JSON := ("[" +
-
-		// ************************************************************************
-		// There are several different message types that will be used by this script, but
-		// you can customize this script for your own needs by modifying the types:
-		// First, you can modify the \"AssetsType\", which will allow you to customize which static
-		// attributes are added to the new PI AF Element that will be created, and second," +
-		// you can modify the \"DataValuesType\", which will allow you to customize this script to send
-		// additional sensor values, in addition to (or instead of) the two shown here
-
-		// This values type is going to be used to send real-time values feel free to rename the
-		// values from \"Raw Sensor Reading 1\" to, say, \"Temperature\", or \"Pressure\"
-		// Note:
-		// all keywords (\"id\", \"type\", \"classification\", etc. are case sensitive!)
-		// For a list of the specific keywords used in these messages," +
-		// see http://omf-docs.readthedocs.io/
-
-		"{" +
-		"\"id\": \"" + DATA_VALUES_MESSAGE_TYPE_NAME + "\"," +
-		"\"type\": \"object\"," +
-		"\"classification\": \"dynamic\"," +
-		"\"properties\": {" +
-		"\"Time\": {" +
-		"\"format\": \"date-time\"," +
-		"\"type\": \"string\"," +
-		"\"isindex\": true" +
-		"}," +
-		"\"Raw Sensor Reading 1\": {" +
-		"\"type\": \"number\"" +
-		"}," +
-		"\"Raw Sensor Reading 2\": {" +
-		"\"type\": \"number\"" +
-		"}" +
-		// For example, to allow you to send a string-type live data value," +
-		// such as \"Status\", you would add
-		//\"Status\": {
-		//   \"type\": \"string\"
-		//}
-		"}" +
-		"}" +
-		"]")
+	// There are several different message types that will be used by this script, but
+	// you can customize this script for your own needs by modifying the types:
+	// First, you can modify the "AssetsType", which will allow you to customize which static
+	// attributes are added to the new PI AF Element that will be created, and second,
+	// you can modify the "DataValuesType", which will allow you to customize this script to send
+	// additional sensor values, in addition to (or instead of) the two that the
+	// built-in sensor.Simulated source reports
+
+	// This values type is going to be used to send real-time values; its properties
+	// are generated from the Schema of every registered sensor.Source, so adding a
+	// source (or changing --sensor-sources) changes what gets sent without editing
+	// this type by hand.
+	// Note: all keywords ("id", "type", "classification", etc.) are case sensitive!
+	// For a list of the specific keywords used in these messages, see http://omf-docs.readthedocs.io/
+	sources := registerSources(logger)
+	dynamicProperties := sources.Schema()
+	dynamicProperties["Time"] = omf.Property{Type: "string", Format: "date-time", IsIndex: true}
+	dynamicType := omf.NewDynamicType(dataValuesMessageTypeName, dynamicProperties)
 
 	// ************************************************************************
-	// Send the DYNAMIC types message, so that these types can be referenced in all later messages
+	// Send the DYNAMIC type message, so that it can be referenced in all later messages
 	// ************************************************************************
 
-	sendOmfMessageToEndpoint("create", "Type", DYNAMIC_TYPES_MESSAGE_JSON)
-
-	// !!! Note: if sending data to OCS, static types are not included!
-	if !SEND_DATA_TO_OSISOFT_CLOUD_SERVICES {
-		STATIC_TYPES_MESSAGE_JSON := ("[" +
-			// This asset type is used to define a PI AF Element that will be created
-			// this type also defines two static string attributes that will be created
-			// as well feel free to rename these or add additional
-			// static attributes for each Element (PI Point attributes will be added later)
-			// The name of this type will also end up being part of the name of the PI AF Element template
-			// that is automatically created
-			"{" +
-			"\"id\": \"" + ASSETS_MESSAGE_TYPE_NAME + "\"," +
-			"\"type\": \"object\"," +
-			"\"classification\": \"static\"," +
-			"\"properties\": {" +
-			"\"Name\": {" +
-			"\"type\": \"string\"," +
-			"\"isindex\": true" +
-			"}," +
-			"\"Device Type\": {" +
-			"\"type\": \"string\"" +
-			"}," +
-			"\"Location\": {" +
-			"\"type\": \"string\"" +
-			"}," +
-			"\"Data Ingress Method\": {" +
-			"\"type\": \"string\"" +
-			"}" +
+	if err := client.Send(ctx, "create", dynamicType); err != nil {
+		fatal(logger, "sending dynamic type", "error", err)
+	}
+
+	// !!! Note: if sending data to OCS, or --send-full-structure=false, static types are not included!
+	if sendFullStructure {
+		// This asset type is used to define a PI AF Element that will be created;
+		// this type also defines static string attributes that will be created as
+		// well, feel free to rename these or add additional static attributes for
+		// each Element (PI Point attributes will be added later). The name of this
+		// type will also end up being part of the name of the PI AF Element
+		// template that is automatically created
+		staticType := omf.NewStaticType(assetsMessageTypeName, map[string]omf.Property{
+			"Name":                {Type: "string", IsIndex: true},
+			"Device Type":         {Type: "string"},
+			"Location":            {Type: "string"},
+			"Data Ingress Method": {Type: "string"},
 			// For example, to add a number-type static
 			// attribute for the device model, you would add
-			// \"Model\": {
-			//   \"type\": \"number\"
-			//}
-			"}" +
-			"}" +
-			"]")
+			// "Model": {Type: "number"},
+		})
 
 		// ************************************************************************
-		// Send the STATIC types message, so that these types can be referenced in all later messages
+		// Send the STATIC type message, so that it can be referenced in all later messages
 		// ************************************************************************
 
-		sendOmfMessageToEndpoint("create", "Type", STATIC_TYPES_MESSAGE_JSON)
+		if err := client.Send(ctx, "create", staticType); err != nil {
+			fatal(logger, "sending static type", "error", err)
+		}
 	}
 
 	// ************************************************************************
-	// Create a JSON packet to define container IDs and the type
-	// (using the types listed above) for each new data events container
+	// Define the container that live data values will be sent to,
+	// using the dynamic type defined above
 	// ************************************************************************
 
 	// The device name that you specified earlier will be used as the AF Element name!
-	NEW_AF_ELEMENT_NAME := DEVICE_NAME
+	newAFElementName := *flagDeviceName
 
-	CONTAINERS_MESSAGE_JSON := ("[" +
-		"{" +
-		"\"id\": \"" + DATA_VALUES_CONTAINER_ID + "\"," +
-		"\"typeid\": \"" + DATA_VALUES_MESSAGE_TYPE_NAME + "\"" +
-		"}" +
-		"]")
+	container := omf.NewContainer(dataValuesContainerID, dataValuesMessageTypeName)
 
 	// ************************************************************************
 	// Send the container message, to instantiate this particular container
 	// we can now directly start sending data to it using its Id
 	// ************************************************************************
 
-	sendOmfMessageToEndpoint("create", "Container", CONTAINERS_MESSAGE_JSON)
-
-	// !!! Note: if sending data to OCS, assets and links are not included!
-	if !SEND_DATA_TO_OSISOFT_CLOUD_SERVICES {
-
-		// ************************************************************************
-		// Create a JSON packet to containing the asset and
-		// linking data for the PI AF asset that will be made
-		// ************************************************************************
+	if err := client.Send(ctx, "create", container); err != nil {
+		fatal(logger, "sending container", "error", err)
+	}
 
-		// Here is where you can specify values for the static PI AF attributes
-		// in this case, we"re auto-populating the Device Type," +
-		// but you can manually hard-code in values if you wish
-		// we also add the LINKS to be made, which will both position the new PI AF
+	// !!! Note: if sending data to OCS, or --send-full-structure=false, assets and links are not included!
+	if sendFullStructure {
+		// Here is where you can specify values for the static PI AF attributes;
+		// in this case, we're auto-populating the Device Type, but you can
+		// manually hard-code in values if you wish
+		asset := omf.AssetValue{
+			TypeID: assetsMessageTypeName,
+			Values: []map[string]any{
+				{
+					"Name":                newAFElementName,
+					"Device Type":         "Type74656",
+					"Location":            *flagDeviceLocation,
+					"Data Ingress Method": "OMF",
+				},
+			},
+		}
+
+		// We also add the LINKS to be made, which will both position the new PI AF
 		// Element, so it will show up in AF, and will associate the PI Points
-		// that will be created with that Element
-		ASSETS_AND_LINKS_MESSAGE_JSON := ("[" +
-			"{" +
-			// This will end up creating a new PI AF Element with
-			// this specific name and static attribute values
-			"\"typeid\": \"" + ASSETS_MESSAGE_TYPE_NAME + "\"," +
-			"\"values\": [" +
-			"{" +
-			"\"Name\":\"" + NEW_AF_ELEMENT_NAME + "\"," +
-			"\"Device Type\": \"" + "Type74656" + "\"," +
-			"\"Location\": \"" + DEVICE_LOCATION + "\"," +
-			"\"Data Ingress Method\": \"OMF\"" +
-			"}" +
-			"]" +
-			"}," +
-			"{" +
-			"\"typeid\": \"__Link\"," +
-			"\"values\": [" +
-			// This first link will locate such a newly created AF Element under
-			// the root PI element targeted by the PI Connector in your target AF database
-			// This was specified in the Connector Relay Admin page note that a new
-			// parent element, with the same name as the PRODUCER_TOKEN, will also be made
-			"{" +
-			"\"Source\": {" +
-			"\"typeid\": \"" + ASSETS_MESSAGE_TYPE_NAME + "\"," +
-			"\"index\": \"_ROOT\"" +
-			"}," +
-			"\"Target\": {" +
-			"\"typeid\": \"" + ASSETS_MESSAGE_TYPE_NAME + "\"," +
-			"\"index\": \"" + NEW_AF_ELEMENT_NAME + "\"" +
-			"}" +
-			"}," +
-			// This second link will map new PI Points (created by messages
-			// sent to the data values container) to a newly create element
-			"{" +
-			"\"Source\": {" +
-			"\"typeid\": \"" + ASSETS_MESSAGE_TYPE_NAME + "\"," +
-			"\"index\":\"" + NEW_AF_ELEMENT_NAME + "\"" +
-			"}," +
-			"\"Target\": {" +
-			"\"containerid\": \"" + DATA_VALUES_CONTAINER_ID + "\"" +
-			"}" +
-			"}" +
-			"]" +
-			"}" +
-			"]")
+		// that will be created with that Element. If --af-path was given, this
+		// walks down through (auto-creating) a parent Element per path segment
+		// instead of linking directly under _ROOT.
+		assets := append(placement.ParentAssets(), asset)
+		links := omf.NewLinkMessage(
+			append(
+				placement.Links(newAFElementName),
+				// This link maps new PI Points (created by messages sent to the
+				// data values container) to the newly created element
+				omf.NewLink(
+					omf.LinkEndpoint{TypeID: assetsMessageTypeName, Index: newAFElementName},
+					omf.LinkEndpoint{ContainerID: dataValuesContainerID},
+				),
+			)...,
+		)
 
 		// ************************************************************************
-		// Send the message to create the PI AF asset it won"t appear in PI AF," +
+		// Send the message to create the PI AF asset; it won't appear in PI AF,
 		// though, because it hasn't yet been positioned...
 		// ************************************************************************
 
-		sendOmfMessageToEndpoint("create", "Data", ASSETS_AND_LINKS_MESSAGE_JSON)
-
+		// The payload must be a flat array of Data messages: each asset
+		// alongside the link message, not assets nested inside their own
+		// array, or a spec-compliant endpoint will reject it.
+		payload := make([]any, 0, len(assets)+1)
+		for _, a := range assets {
+			payload = append(payload, a)
+		}
+		payload = append(payload, links)
+
+		if err := client.Send(ctx, "create", payload); err != nil {
+			fatal(logger, "sending assets and links", "error", err)
+		}
 	}
 
 	// ************************************************************************
-	// Initialize sensors prior to sending data (if needed), using the function defined earlier
+	// Finally, loop until ctx is canceled (by SIGINT/SIGTERM), sending
+	// values conforming to the value type that we defined earlier
 	// ************************************************************************
 
-	initializeSensors()
-
-	// ************************************************************************
-	// Finally, loop indefinitely, sending random events
-	// conforming to the value type that we defined earlier
-	// ************************************************************************
-
-	fmt.Println(
-		"\n--- Now sending live data every " + string(NUMBER_OF_SECONDS_BETWEEN_VALUE_MESSAGES) +
-			" second(s) for device \"" + NEW_AF_ELEMENT_NAME + "\"... (press CTRL+C to quit at any time)\n")
-	if !SEND_DATA_TO_OSISOFT_CLOUD_SERVICES {
-		fmt.Println(
-			"--- (Look for a new AF Element named \"" + NEW_AF_ELEMENT_NAME + "\".)\n")
+	logger.Info("sending live data", "interval", flagInterval.String(), "device", newAFElementName)
+	if sendFullStructure {
+		logger.Info("look for a new AF Element", "name", newAFElementName)
 	}
-	for {
-		// Call the custom function that builds a JSON object that
-		// contains new data values see the beginning of this script
-		VALUES_MESSAGE_JSON := createDataValuesMessage()
 
-		// Send the JSON message to the target URL
-		sendOmfMessageToEndpoint("create", "Data", VALUES_MESSAGE_JSON)
-
-		// Send the next message after the required interval
-		time.Sleep(time.Duration(NUMBER_OF_SECONDS_BETWEEN_VALUE_MESSAGES) * time.Second)
+	ticker := time.NewTicker(*flagInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			logger.Info("shutting down, draining queue")
+			drainCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+			sender.Drain(drainCtx)
+			cancel()
+			return
+		case <-ticker.C:
+			// Call the custom function that builds the data values message; see
+			// the beginning of this script
+			valuesMessage := createDataValuesMessage(ctx, logger, sources, dataValuesContainerID)
+
+			// Buffer the message rather than sending it directly, so it survives
+			// the endpoint being temporarily unreachable; the sender started
+			// above drains the queue to the endpoint in the background.
+			if err := q.Enqueue(valuesMessage); err != nil {
+				logger.Error("queuing data values", "error", err)
+			}
+		}
 	}
 }
@@ -0,0 +1,83 @@
+// Copyright 2018 OSIsoft, LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License")
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// <http://www.apache.org/licenses/LICENSE-2.0>
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package af builds the chain of OMF __Link messages that places an
+// Element at a specific Asset Framework location, such as
+// "Site/Building/Room", auto-creating the parent Elements along the way.
+package af
+
+import "github.com/osisoft/OMF-Samples/go/omf"
+
+// Placement describes where in the Asset Framework hierarchy an Element
+// should be positioned: under _ROOT directly, or under a chain of parent
+// Elements named by Path (for example, []string{"Site", "Building",
+// "Room"}). Parent Elements all use TypeID, the same static asset type as
+// the Element being placed.
+type Placement struct {
+	TypeID string
+	Path   []string
+}
+
+// New builds a Placement from a slash-separated path like
+// "Site/Building/Room". An empty path places the Element directly under
+// _ROOT, matching the sample's original behavior.
+func New(typeID, path string) Placement {
+	return Placement{TypeID: typeID, Path: splitNonEmpty(path)}
+}
+
+// ParentAssets returns one AssetValue per path segment, so the parent
+// Elements exist before the links below reference them. It's empty when
+// Path is empty.
+func (p Placement) ParentAssets() []omf.AssetValue {
+	assets := make([]omf.AssetValue, 0, len(p.Path))
+	for _, segment := range p.Path {
+		assets = append(assets, omf.AssetValue{
+			TypeID: p.TypeID,
+			Values: []map[string]any{{"Name": segment}},
+		})
+	}
+	return assets
+}
+
+// Links returns the chain of __Link messages that walks from _ROOT down
+// through each parent in Path, ending with a link from the last parent
+// (or _ROOT, if Path is empty) to elementName.
+func (p Placement) Links(elementName string) []omf.LinkValue {
+	parent := omf.LinkEndpoint{TypeID: p.TypeID, Index: "_ROOT"}
+
+	links := make([]omf.LinkValue, 0, len(p.Path)+1)
+	for _, segment := range p.Path {
+		child := omf.LinkEndpoint{TypeID: p.TypeID, Index: segment}
+		links = append(links, omf.NewLink(parent, child))
+		parent = child
+	}
+	links = append(links, omf.NewLink(parent, omf.LinkEndpoint{TypeID: p.TypeID, Index: elementName}))
+	return links
+}
+
+// splitNonEmpty splits path on "/", discarding empty segments so that
+// leading, trailing, or doubled slashes don't produce blank AF Elements.
+func splitNonEmpty(path string) []string {
+	var segments []string
+	start := 0
+	for i := 0; i <= len(path); i++ {
+		if i == len(path) || path[i] == '/' {
+			if i > start {
+				segments = append(segments, path[start:i])
+			}
+			start = i + 1
+		}
+	}
+	return segments
+}
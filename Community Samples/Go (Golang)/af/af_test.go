@@ -0,0 +1,72 @@
+// Copyright 2018 OSIsoft, LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License")
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// <http://www.apache.org/licenses/LICENSE-2.0>
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package af
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/osisoft/OMF-Samples/go/omf"
+)
+
+func TestPlacementNoPath(t *testing.T) {
+	p := New("AssetsType", "")
+
+	if got := p.ParentAssets(); len(got) != 0 {
+		t.Errorf("ParentAssets() = %v, want empty", got)
+	}
+
+	want := []omf.LinkValue{
+		omf.NewLink(
+			omf.LinkEndpoint{TypeID: "AssetsType", Index: "_ROOT"},
+			omf.LinkEndpoint{TypeID: "AssetsType", Index: "Device1"},
+		),
+	}
+	if got := p.Links("Device1"); !reflect.DeepEqual(got, want) {
+		t.Errorf("Links(%q) = %+v, want %+v", "Device1", got, want)
+	}
+}
+
+func TestPlacementWithPath(t *testing.T) {
+	p := New("AssetsType", "Site/Building/Room")
+
+	wantAssets := []omf.AssetValue{
+		{TypeID: "AssetsType", Values: []map[string]any{{"Name": "Site"}}},
+		{TypeID: "AssetsType", Values: []map[string]any{{"Name": "Building"}}},
+		{TypeID: "AssetsType", Values: []map[string]any{{"Name": "Room"}}},
+	}
+	if got := p.ParentAssets(); !reflect.DeepEqual(got, wantAssets) {
+		t.Errorf("ParentAssets() = %+v, want %+v", got, wantAssets)
+	}
+
+	wantLinks := []omf.LinkValue{
+		omf.NewLink(omf.LinkEndpoint{TypeID: "AssetsType", Index: "_ROOT"}, omf.LinkEndpoint{TypeID: "AssetsType", Index: "Site"}),
+		omf.NewLink(omf.LinkEndpoint{TypeID: "AssetsType", Index: "Site"}, omf.LinkEndpoint{TypeID: "AssetsType", Index: "Building"}),
+		omf.NewLink(omf.LinkEndpoint{TypeID: "AssetsType", Index: "Building"}, omf.LinkEndpoint{TypeID: "AssetsType", Index: "Room"}),
+		omf.NewLink(omf.LinkEndpoint{TypeID: "AssetsType", Index: "Room"}, omf.LinkEndpoint{TypeID: "AssetsType", Index: "Device1"}),
+	}
+	if got := p.Links("Device1"); !reflect.DeepEqual(got, wantLinks) {
+		t.Errorf("Links(%q) = %+v, want %+v", "Device1", got, wantLinks)
+	}
+}
+
+func TestPlacementPathWithStraySlashes(t *testing.T) {
+	p := New("AssetsType", "/Site//Building/")
+
+	want := []string{"Site", "Building"}
+	if !reflect.DeepEqual(p.Path, want) {
+		t.Errorf("Path = %v, want %v", p.Path, want)
+	}
+}
@@ -0,0 +1,322 @@
+// Copyright 2018 OSIsoft, LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License")
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// <http://www.apache.org/licenses/LICENSE-2.0>
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package endpoint describes the different places an OMF message can be
+// sent, since the header names, auth scheme, URL suffix, and supported
+// OMF version all depend on which product is on the receiving end.
+package endpoint
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Kind identifies the product family on the other end of the wire, as
+// passed in via the --endpoint flag.
+type Kind string
+
+// The endpoint kinds supported by this sample.
+const (
+	KindRelay  Kind = "relay"  // PI Connector Relay (the original, now-discontinued, target of this sample)
+	KindWebAPI Kind = "webapi" // PI Web API OMF endpoint
+	KindEDS    Kind = "eds"    // Edge Data Store
+	KindOCS    Kind = "ocs"    // OSIsoft Cloud Services / AVEVA Data Hub
+)
+
+// Endpoint is a destination for OMF messages. Each Kind knows its own auth
+// scheme, URL suffix, and which OMF versions it understands; everything
+// else in this sample talks to Endpoint rather than any one product.
+type Endpoint interface {
+	// Kind reports which product family this endpoint targets.
+	Kind() Kind
+
+	// MessageURL returns the full URL that OMF messages should be POSTed to.
+	MessageURL() string
+
+	// SupportedVersions lists the OMF versions this endpoint can accept,
+	// newest first.
+	SupportedVersions() []string
+
+	// AuthHeaders returns the headers (if any) needed to authenticate a
+	// request, fetching or refreshing a token if the scheme requires it.
+	AuthHeaders(ctx context.Context) (map[string]string, error)
+
+	// SuppressStaticMessages reports whether Asset and Link messages
+	// should be skipped for this endpoint, since it has no Asset
+	// Framework to place them in.
+	SuppressStaticMessages() bool
+}
+
+// Config holds the connection details needed to build any Endpoint kind.
+// Not every field is used by every kind; see New for which fields apply
+// to which Kind.
+type Config struct {
+	Kind Kind
+
+	// BaseURL is the root of the target, e.g. "https://mypiserver:5461"
+	// or "https://dat-a.osisoft.com". The per-kind message path
+	// ("/omf" or "/ingress/messages") is appended automatically.
+	BaseURL string
+
+	// ProducerToken authorizes this producer with a PI Connector Relay.
+	ProducerToken string
+
+	// Username/Password are used for PI Web API's Basic auth.
+	Username string
+	Password string
+
+	// ClientID/ClientSecret/TokenURL are used for the OCS/ADH OAuth
+	// client-credentials flow.
+	ClientID     string
+	ClientSecret string
+	TokenURL     string
+
+	// InsecureSkipVerify is not read by New. TLS certificate verification
+	// for OMF messages is set by the http.Client passed to
+	// NegotiateVersion and omf.NewClient, and the OCS/ADH token request
+	// always verifies certificates.
+	InsecureSkipVerify bool
+}
+
+// New builds the Endpoint implementation for cfg.Kind.
+func New(cfg Config) (Endpoint, error) {
+	base := strings.TrimRight(cfg.BaseURL, "/")
+	if base == "" {
+		return nil, fmt.Errorf("endpoint: BaseURL is required")
+	}
+	if _, err := url.Parse(base); err != nil {
+		return nil, fmt.Errorf("endpoint: invalid BaseURL %q: %w", cfg.BaseURL, err)
+	}
+
+	switch cfg.Kind {
+	case KindRelay:
+		return &relayEndpoint{base: base, producerToken: cfg.ProducerToken}, nil
+	case KindWebAPI:
+		return &webAPIEndpoint{base: base, username: cfg.Username, password: cfg.Password}, nil
+	case KindEDS:
+		return &edsEndpoint{base: base}, nil
+	case KindOCS:
+		if cfg.ClientID == "" || cfg.ClientSecret == "" || cfg.TokenURL == "" {
+			return nil, fmt.Errorf("endpoint: ocs requires ClientID, ClientSecret, and TokenURL")
+		}
+		return &ocsEndpoint{
+			base: base,
+			tokenSource: &clientCredentialsTokenSource{
+				clientID:     cfg.ClientID,
+				clientSecret: cfg.ClientSecret,
+				tokenURL:     cfg.TokenURL,
+			},
+		}, nil
+	default:
+		return nil, fmt.Errorf("endpoint: unknown kind %q (want one of %q, %q, %q, %q)",
+			cfg.Kind, KindRelay, KindWebAPI, KindEDS, KindOCS)
+	}
+}
+
+// relayEndpoint targets the (now-discontinued) PI Connector Relay, the
+// original target of this sample. It only ever spoke OMF 1.0 and
+// authorizes with a shared producer token header rather than per-request
+// credentials.
+type relayEndpoint struct {
+	base          string
+	producerToken string
+}
+
+func (e *relayEndpoint) Kind() Kind                   { return KindRelay }
+func (e *relayEndpoint) MessageURL() string           { return e.base + "/ingress/messages" }
+func (e *relayEndpoint) SupportedVersions() []string  { return []string{"1.0"} }
+func (e *relayEndpoint) SuppressStaticMessages() bool { return false }
+func (e *relayEndpoint) AuthHeaders(context.Context) (map[string]string, error) {
+	return map[string]string{"producertoken": e.producerToken}, nil
+}
+
+// webAPIEndpoint targets PI Web API's OMF endpoint, which authenticates
+// with Basic auth against a Windows or Basic-auth-mapped PI account.
+type webAPIEndpoint struct {
+	base     string
+	username string
+	password string
+}
+
+func (e *webAPIEndpoint) Kind() Kind                   { return KindWebAPI }
+func (e *webAPIEndpoint) MessageURL() string           { return e.base + "/omf" }
+func (e *webAPIEndpoint) SupportedVersions() []string  { return []string{"1.2", "1.1", "1.0"} }
+func (e *webAPIEndpoint) SuppressStaticMessages() bool { return false }
+func (e *webAPIEndpoint) AuthHeaders(context.Context) (map[string]string, error) {
+	req, _ := http.NewRequest(http.MethodGet, e.base, nil)
+	req.SetBasicAuth(e.username, e.password)
+	return map[string]string{"Authorization": req.Header.Get("Authorization")}, nil
+}
+
+// edsEndpoint targets a local Edge Data Store instance. EDS has no Asset
+// Framework of its own, so static Asset/Link messages are suppressed, and
+// it requires no authentication since it only listens on localhost.
+type edsEndpoint struct {
+	base string
+}
+
+func (e *edsEndpoint) Kind() Kind                   { return KindEDS }
+func (e *edsEndpoint) MessageURL() string           { return e.base + "/omf" }
+func (e *edsEndpoint) SupportedVersions() []string  { return []string{"1.2", "1.1", "1.0"} }
+func (e *edsEndpoint) SuppressStaticMessages() bool { return true }
+func (e *edsEndpoint) AuthHeaders(context.Context) (map[string]string, error) {
+	return nil, nil
+}
+
+// ocsEndpoint targets OSIsoft Cloud Services / AVEVA Data Hub. Like EDS,
+// it has no Asset Framework, so static messages are suppressed. Auth is a
+// bearer token obtained via the OAuth2 client-credentials flow and cached
+// (and refreshed) by tokenSource.
+type ocsEndpoint struct {
+	base        string
+	tokenSource *clientCredentialsTokenSource
+}
+
+func (e *ocsEndpoint) Kind() Kind                   { return KindOCS }
+func (e *ocsEndpoint) MessageURL() string           { return e.base + "/ingress/messages" }
+func (e *ocsEndpoint) SupportedVersions() []string  { return []string{"1.2", "1.1", "1.0"} }
+func (e *ocsEndpoint) SuppressStaticMessages() bool { return true }
+func (e *ocsEndpoint) AuthHeaders(ctx context.Context) (map[string]string, error) {
+	token, err := e.tokenSource.Token(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("endpoint: fetching OCS/ADH token: %w", err)
+	}
+	return map[string]string{"Authorization": "Bearer " + token}, nil
+}
+
+// clientCredentialsTokenSource implements the OAuth2 client-credentials
+// grant against tokenURL and caches the result until shortly before it
+// expires, so AuthHeaders doesn't fetch a fresh token on every message.
+type clientCredentialsTokenSource struct {
+	clientID     string
+	clientSecret string
+	tokenURL     string
+
+	mu        sync.Mutex
+	cached    string
+	expiresAt time.Time
+}
+
+func (s *clientCredentialsTokenSource) Token(ctx context.Context) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.cached != "" && time.Now().Before(s.expiresAt) {
+		return s.cached, nil
+	}
+
+	form := url.Values{
+		"grant_type":    {"client_credentials"},
+		"client_id":     {s.clientID},
+		"client_secret": {s.clientSecret},
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.tokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("token endpoint returned %s", resp.Status)
+	}
+
+	var body struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int    `json:"expires_in"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return "", fmt.Errorf("decoding token response: %w", err)
+	}
+
+	s.cached = body.AccessToken
+	s.expiresAt = time.Now().Add(time.Duration(body.ExpiresIn)*time.Second - 30*time.Second)
+	return s.cached, nil
+}
+
+// NegotiateVersion probes ep with a lightweight request for each version it
+// claims to support, newest first, and returns the first one the server
+// actually accepts. This matters because an endpoint's SupportedVersions
+// is a compile-time guess about the product family; the live server (an
+// older PI Web API patch, say) may only accept an older version in
+// practice.
+func NegotiateVersion(ctx context.Context, ep Endpoint, httpClient *http.Client) (string, error) {
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+
+	var lastErr error
+	for _, version := range ep.SupportedVersions() {
+		ok, err := probeVersion(ctx, ep, httpClient, version)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		if ok {
+			return version, nil
+		}
+	}
+	if lastErr != nil {
+		return "", fmt.Errorf("endpoint: negotiating OMF version: %w", lastErr)
+	}
+	return "", fmt.Errorf("endpoint: %s accepted none of %q", ep.Kind(), ep.SupportedVersions())
+}
+
+// probeVersion sends an empty Type message at the given version. A 400
+// ("omfversion not supported") means the version is not accepted; a 5xx is
+// unrelated to version support and is returned as an error, which
+// NegotiateVersion reports if no version is accepted; any other response
+// counts as acceptance, since an empty array is valid at every OMF version.
+func probeVersion(ctx context.Context, ep Endpoint, httpClient *http.Client, version string) (bool, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, ep.MessageURL(), strings.NewReader("[]"))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("messagetype", "Type")
+	req.Header.Set("action", "create")
+	req.Header.Set("messageformat", "JSON")
+	req.Header.Set("omfversion", version)
+
+	headers, err := ep.AuthHeaders(ctx)
+	if err != nil {
+		return false, err
+	}
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return false, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusBadRequest {
+		return false, nil
+	}
+	if resp.StatusCode >= 500 {
+		return false, fmt.Errorf("probing OMF %s: %s", version, resp.Status)
+	}
+	return true, nil
+}
@@ -0,0 +1,59 @@
+// Copyright 2018 OSIsoft, LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License")
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// <http://www.apache.org/licenses/LICENSE-2.0>
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package metrics exposes the sample's store-and-forward queue depth and
+// drop count as Prometheus metrics.
+package metrics
+
+import (
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// QueueDepth is the number of omf.DataMessage values currently buffered
+// in the store-and-forward queue, waiting to be sent.
+var QueueDepth = promauto.NewGauge(prometheus.GaugeOpts{
+	Name: "omf_queue_depth",
+	Help: "Number of OMF data messages currently buffered, waiting to be sent.",
+})
+
+// The DroppedTotal "reason" label values: why a data message never made it
+// to the endpoint.
+const (
+	// DroppedReasonOverflow means the queue reached its configured
+	// maximum depth and the oldest message was evicted to make room.
+	DroppedReasonOverflow = "overflow"
+	// DroppedReasonRejected means the endpoint permanently rejected the
+	// message (e.g. a bad schema), so retrying it would never succeed.
+	DroppedReasonRejected = "rejected"
+)
+
+// DroppedTotal counts messages discarded before reaching the endpoint,
+// labeled by reason: queue overflow (capacity problem, see
+// DroppedReasonOverflow) versus a permanent endpoint rejection
+// (data/schema problem, see DroppedReasonRejected), since on-call needs to
+// tell the two apart.
+var DroppedTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "omf_queue_dropped_total",
+	Help: "Total number of OMF data messages dropped before reaching the endpoint, labeled by reason (overflow or rejected).",
+}, []string{"reason"})
+
+// Handler serves the registered metrics in the Prometheus text exposition
+// format, for mounting at a path such as "/metrics".
+func Handler() http.Handler {
+	return promhttp.Handler()
+}
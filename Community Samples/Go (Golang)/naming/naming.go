@@ -0,0 +1,63 @@
+// Copyright 2018 OSIsoft, LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License")
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// <http://www.apache.org/licenses/LICENSE-2.0>
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package naming builds PI point / container names from a configurable
+// template, rather than a single hard-coded naming convention, so a
+// deployment can match whatever naming scheme its PI points already use.
+package naming
+
+import (
+	"strings"
+	"text/template"
+)
+
+// Fields are the values a Scheme's template can reference.
+type Fields struct {
+	Device      string
+	Measurement string
+}
+
+// Scheme renders Fields into a name using a text/template.Template.
+type Scheme struct {
+	tmpl *template.Template
+}
+
+// New parses pattern as a naming scheme. For convenience, the shorthand
+// tokens "{device}" and "{measurement}" are accepted in addition to full
+// Go template syntax ("{{.Device}}", "{{.Measurement}}"), so a pattern
+// like "{device}.{measurement}" works without escaping. Patterns that use
+// "{{" are left untouched and parsed as ordinary Go templates.
+func New(pattern string) (*Scheme, error) {
+	if !strings.Contains(pattern, "{{") {
+		pattern = strings.NewReplacer(
+			"{device}", "{{.Device}}",
+			"{measurement}", "{{.Measurement}}",
+		).Replace(pattern)
+	}
+
+	tmpl, err := template.New("naming-scheme").Parse(pattern)
+	if err != nil {
+		return nil, err
+	}
+	return &Scheme{tmpl: tmpl}, nil
+}
+
+// Name renders fields through the scheme's template.
+func (s *Scheme) Name(fields Fields) (string, error) {
+	var b strings.Builder
+	if err := s.tmpl.Execute(&b, fields); err != nil {
+		return "", err
+	}
+	return b.String(), nil
+}
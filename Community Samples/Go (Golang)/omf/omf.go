@@ -0,0 +1,255 @@
+// Copyright 2018 OSIsoft, LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License")
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// <http://www.apache.org/licenses/LICENSE-2.0>
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package omf builds OMF Type, Container, and Data messages as typed Go
+// values rather than hand-concatenated JSON strings, so that a missing
+// comma or a misspelled keyword is a compile error instead of a rejected
+// HTTP request.
+package omf
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/osisoft/OMF-Samples/go/endpoint"
+)
+
+// Property describes one field of a Type's "properties" object, e.g. a
+// "Temperature" reading of type "number", or the "Time" index.
+type Property struct {
+	Type    string `json:"type"`
+	Format  string `json:"format,omitempty"`
+	IsIndex bool   `json:"isindex,omitempty"`
+}
+
+// TypeDef is an OMF "Type" message: the schema for either a dynamic
+// (time-series) container or a static (Asset Framework element) value.
+type TypeDef struct {
+	ID             string              `json:"id"`
+	Type           string              `json:"type"`
+	Classification string              `json:"classification"`
+	Properties     map[string]Property `json:"properties"`
+}
+
+// NewDynamicType builds the TypeDef for a time-series stream, such as the
+// live sensor readings sent to a Container.
+func NewDynamicType(id string, properties map[string]Property) TypeDef {
+	return TypeDef{ID: id, Type: "object", Classification: "dynamic", Properties: properties}
+}
+
+// NewStaticType builds the TypeDef for a PI AF Element's static
+// attributes.
+func NewStaticType(id string, properties map[string]Property) TypeDef {
+	return TypeDef{ID: id, Type: "object", Classification: "static", Properties: properties}
+}
+
+// Container is an OMF "Container" message: an instance of a dynamic
+// TypeDef that live Data values can be sent against.
+type Container struct {
+	ID     string `json:"id"`
+	TypeID string `json:"typeid"`
+}
+
+// NewContainer builds a Container of the given TypeDef id.
+func NewContainer(id, typeID string) Container {
+	return Container{ID: id, TypeID: typeID}
+}
+
+// DataMessage carries one batch of live values for a Container.
+type DataMessage struct {
+	ContainerID string           `json:"containerid"`
+	Values      []map[string]any `json:"values"`
+}
+
+// AssetValue is an OMF "Data" message that creates or updates a static
+// (Asset Framework) value, such as a new PI AF Element.
+type AssetValue struct {
+	TypeID string           `json:"typeid"`
+	Values []map[string]any `json:"values"`
+}
+
+// LinkEndpoint identifies one side of a LinkValue: either an element
+// referenced by its TypeID and static index, or a container referenced by
+// ContainerID.
+type LinkEndpoint struct {
+	TypeID      string `json:"typeid,omitempty"`
+	Index       string `json:"index,omitempty"`
+	ContainerID string `json:"containerid,omitempty"`
+}
+
+// LinkValue is a single parent/child relationship between two
+// LinkEndpoints, e.g. positioning an AF Element under its parent, or
+// associating a Container's PI Points with their Element.
+type LinkValue struct {
+	Source LinkEndpoint `json:"Source"`
+	Target LinkEndpoint `json:"Target"`
+}
+
+// NewLink builds a LinkValue from source to target.
+func NewLink(source, target LinkEndpoint) LinkValue {
+	return LinkValue{Source: source, Target: target}
+}
+
+// LinkMessage is the OMF "Data" message that carries one or more
+// LinkValues against the well-known "__Link" type.
+type LinkMessage struct {
+	TypeID string      `json:"typeid"`
+	Values []LinkValue `json:"values"`
+}
+
+// NewLinkMessage wraps links in the envelope the OMF spec expects them
+// sent in: a Data message against the "__Link" type.
+func NewLinkMessage(links ...LinkValue) LinkMessage {
+	return LinkMessage{TypeID: "__Link", Values: links}
+}
+
+// Client sends OMF messages to an endpoint.Endpoint, handling the
+// per-endpoint auth headers and the required OMF headers.
+type Client struct {
+	Endpoint   endpoint.Endpoint
+	HTTPClient *http.Client
+	OMFVersion string
+
+	// Logger receives a debug-level log of every request and response
+	// body sent through Send. It defaults to slog.Default().
+	Logger *slog.Logger
+}
+
+// NewClient builds a Client that sends messages to ep at omfVersion using
+// httpClient. If httpClient is nil, http.DefaultClient is used.
+func NewClient(ep endpoint.Endpoint, httpClient *http.Client, omfVersion string) *Client {
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	return &Client{Endpoint: ep, HTTPClient: httpClient, OMFVersion: omfVersion, Logger: slog.Default()}
+}
+
+// Send marshals msg and POSTs it to c.Endpoint as the given action
+// ("create", "update", or "delete"). The OMF "messagetype" header
+// ("Type", "Container", or "Data") is inferred from msg's Go type.
+func (c *Client) Send(ctx context.Context, action string, msg any) error {
+	messageType, err := messageTypeOf(msg)
+	if err != nil {
+		return err
+	}
+
+	body, err := json.Marshal(msg)
+	if err != nil {
+		return fmt.Errorf("omf: marshaling %s message: %w", messageType, err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.Endpoint.MessageURL(), bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("omf: building request: %w", err)
+	}
+
+	authHeaders, err := c.Endpoint.AuthHeaders(ctx)
+	if err != nil {
+		return fmt.Errorf("omf: building auth headers: %w", err)
+	}
+	for name, value := range authHeaders {
+		req.Header.Add(name, value)
+	}
+	req.Header.Add("messagetype", messageType)
+	req.Header.Add("action", action)
+	req.Header.Add("messageformat", "JSON")
+	req.Header.Add("omfversion", c.OMFVersion)
+
+	c.logger().Debug("sending OMF message", "messagetype", messageType, "action", action, "body", string(body))
+
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return &SendError{Err: fmt.Errorf("omf: sending %s message: %w", messageType, err)}
+	}
+	defer resp.Body.Close()
+
+	respBody, _ := io.ReadAll(resp.Body)
+	c.logger().Debug("received OMF response", "messagetype", messageType, "status", resp.Status, "body", string(respBody))
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return &SendError{
+			StatusCode: resp.StatusCode,
+			RetryAfter: parseRetryAfter(resp.Header.Get("Retry-After")),
+			Err:        fmt.Errorf("omf: %s message rejected: %s", messageType, resp.Status),
+		}
+	}
+	return nil
+}
+
+// logger returns c.Logger, falling back to slog.Default() for a Client
+// built without NewClient.
+func (c *Client) logger() *slog.Logger {
+	if c.Logger != nil {
+		return c.Logger
+	}
+	return slog.Default()
+}
+
+// SendError is returned by Client.Send when a message could not be
+// delivered, either because the request never reached the endpoint
+// (StatusCode 0) or because the endpoint rejected it.
+type SendError struct {
+	StatusCode int
+	RetryAfter time.Duration
+	Err        error
+}
+
+func (e *SendError) Error() string { return e.Err.Error() }
+
+func (e *SendError) Unwrap() error { return e.Err }
+
+// Temporary reports whether retrying the send later is worth attempting:
+// the request never reached the server, the server is rate-limiting
+// (429), or it reported a server-side failure (5xx).
+func (e *SendError) Temporary() bool {
+	return e.StatusCode == 0 || e.StatusCode == http.StatusTooManyRequests || e.StatusCode >= 500
+}
+
+// parseRetryAfter parses an HTTP Retry-After header given in delta-seconds
+// form. It returns 0 if the header is absent or not a delta-seconds value.
+func parseRetryAfter(header string) time.Duration {
+	if header == "" {
+		return 0
+	}
+	seconds, err := strconv.Atoi(header)
+	if err != nil || seconds < 0 {
+		return 0
+	}
+	return time.Duration(seconds) * time.Second
+}
+
+// messageTypeOf maps a Go payload to the OMF "messagetype" header it
+// corresponds to.
+func messageTypeOf(msg any) (string, error) {
+	switch msg.(type) {
+	case TypeDef, []TypeDef:
+		return "Type", nil
+	case Container, []Container:
+		return "Container", nil
+	case DataMessage, []DataMessage,
+		AssetValue, []AssetValue,
+		LinkMessage, []LinkMessage,
+		[]any:
+		return "Data", nil
+	default:
+		return "", fmt.Errorf("omf: unsupported message payload type %T", msg)
+	}
+}
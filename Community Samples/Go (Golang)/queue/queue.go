@@ -0,0 +1,185 @@
+// Copyright 2018 OSIsoft, LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License")
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// <http://www.apache.org/licenses/LICENSE-2.0>
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package queue is a durable store-and-forward buffer for omf.DataMessage
+// values, so readings collected while the target OMF endpoint is down
+// survive a process restart and are replayed once connectivity returns.
+package queue
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"go.etcd.io/bbolt"
+
+	"github.com/osisoft/OMF-Samples/go/omf"
+)
+
+var bucketName = []byte("data-messages")
+
+// Queue is a BoltDB-backed FIFO of pending omf.DataMessage values.
+type Queue struct {
+	db       *bbolt.DB
+	maxDepth int
+
+	onOverflow    func(dropped omf.DataMessage)
+	onDepthChange func(depth int)
+}
+
+// Option configures a Queue constructed by Open.
+type Option func(*Queue)
+
+// WithMaxDepth bounds the queue to maxDepth entries; once full, Enqueue
+// drops the oldest entry to make room and reports it via onOverflow, so a
+// downed endpoint applies backpressure instead of growing the queue file
+// without limit. A maxDepth of 0 (the default) means unbounded.
+func WithMaxDepth(maxDepth int, onOverflow func(dropped omf.DataMessage)) Option {
+	return func(q *Queue) {
+		q.maxDepth = maxDepth
+		q.onOverflow = onOverflow
+	}
+}
+
+// WithDepthGauge reports the queue's depth to onDepthChange after every
+// Enqueue, so a caller can mirror it into a metric without this package
+// depending on a metrics library.
+func WithDepthGauge(onDepthChange func(depth int)) Option {
+	return func(q *Queue) {
+		q.onDepthChange = onDepthChange
+	}
+}
+
+// Open opens (creating if necessary) the BoltDB file at path as a Queue.
+func Open(path string, opts ...Option) (*Queue, error) {
+	db, err := bbolt.Open(path, 0600, nil)
+	if err != nil {
+		return nil, fmt.Errorf("queue: opening %s: %w", path, err)
+	}
+	if err := db.Update(func(tx *bbolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(bucketName)
+		return err
+	}); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("queue: creating bucket: %w", err)
+	}
+
+	q := &Queue{db: db}
+	for _, opt := range opts {
+		opt(q)
+	}
+	return q, nil
+}
+
+// Close closes the underlying BoltDB file.
+func (q *Queue) Close() error {
+	return q.db.Close()
+}
+
+// Enqueue appends msg to the tail of the queue. If a maxDepth was set via
+// WithMaxDepth and the queue is full, the oldest entry is dropped to make
+// room and reported through onOverflow.
+func (q *Queue) Enqueue(msg omf.DataMessage) error {
+	body, err := json.Marshal(msg)
+	if err != nil {
+		return fmt.Errorf("queue: marshaling message: %w", err)
+	}
+
+	err = q.db.Update(func(tx *bbolt.Tx) error {
+		bucket := tx.Bucket(bucketName)
+
+		if q.maxDepth > 0 && bucket.Stats().KeyN >= q.maxDepth {
+			cursor := bucket.Cursor()
+			oldestKey, oldestValue := cursor.First()
+			if oldestKey != nil {
+				var dropped omf.DataMessage
+				if q.onOverflow != nil && json.Unmarshal(oldestValue, &dropped) == nil {
+					q.onOverflow(dropped)
+				}
+				if err := bucket.Delete(oldestKey); err != nil {
+					return err
+				}
+			}
+		}
+
+		seq, err := bucket.NextSequence()
+		if err != nil {
+			return err
+		}
+		return bucket.Put(itob(seq), body)
+	})
+	if err != nil {
+		return err
+	}
+
+	if q.onDepthChange != nil {
+		if depth, depthErr := q.Depth(); depthErr == nil {
+			q.onDepthChange(depth)
+		}
+	}
+	return nil
+}
+
+// Peek returns up to n of the oldest queued messages, along with the keys
+// needed to Remove them once they've been sent successfully. It does not
+// remove them from the queue.
+func (q *Queue) Peek(n int) (messages []omf.DataMessage, keys [][]byte, err error) {
+	err = q.db.View(func(tx *bbolt.Tx) error {
+		cursor := tx.Bucket(bucketName).Cursor()
+		for k, v := cursor.First(); k != nil && len(messages) < n; k, v = cursor.Next() {
+			var msg omf.DataMessage
+			if err := json.Unmarshal(v, &msg); err != nil {
+				return fmt.Errorf("queue: unmarshaling queued message: %w", err)
+			}
+			messages = append(messages, msg)
+			keys = append(keys, append([]byte(nil), k...))
+		}
+		return nil
+	})
+	return messages, keys, err
+}
+
+// Remove deletes the given keys, as returned by a prior Peek, once their
+// messages have been sent successfully.
+func (q *Queue) Remove(keys [][]byte) error {
+	return q.db.Update(func(tx *bbolt.Tx) error {
+		bucket := tx.Bucket(bucketName)
+		for _, k := range keys {
+			if err := bucket.Delete(k); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Depth returns the number of messages currently queued.
+func (q *Queue) Depth() (int, error) {
+	var depth int
+	err := q.db.View(func(tx *bbolt.Tx) error {
+		depth = tx.Bucket(bucketName).Stats().KeyN
+		return nil
+	})
+	return depth, err
+}
+
+// itob encodes seq as a big-endian key, so bucket iteration order matches
+// enqueue order.
+func itob(seq uint64) []byte {
+	b := make([]byte, 8)
+	for i := 7; i >= 0; i-- {
+		b[i] = byte(seq)
+		seq >>= 8
+	}
+	return b
+}
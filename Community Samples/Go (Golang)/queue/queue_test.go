@@ -0,0 +1,155 @@
+// Copyright 2018 OSIsoft, LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License")
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// <http://www.apache.org/licenses/LICENSE-2.0>
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package queue
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/osisoft/OMF-Samples/go/omf"
+)
+
+func openTestQueue(t *testing.T, opts ...Option) *Queue {
+	t.Helper()
+	q, err := Open(filepath.Join(t.TempDir(), "queue.db"), opts...)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	t.Cleanup(func() { q.Close() })
+	return q
+}
+
+func dataMessage(containerID string) omf.DataMessage {
+	return omf.DataMessage{ContainerID: containerID, Values: []map[string]any{{"Temperature": 1}}}
+}
+
+func TestEnqueueUnbounded(t *testing.T) {
+	q := openTestQueue(t)
+
+	for i := 0; i < 5; i++ {
+		if err := q.Enqueue(dataMessage("c1")); err != nil {
+			t.Fatalf("Enqueue: %v", err)
+		}
+	}
+
+	depth, err := q.Depth()
+	if err != nil {
+		t.Fatalf("Depth: %v", err)
+	}
+	if depth != 5 {
+		t.Errorf("Depth() = %d, want 5", depth)
+	}
+}
+
+func TestEnqueueOverflowDropsOldest(t *testing.T) {
+	var dropped []omf.DataMessage
+	q := openTestQueue(t, WithMaxDepth(3, func(msg omf.DataMessage) {
+		dropped = append(dropped, msg)
+	}))
+
+	for i := 0; i < 5; i++ {
+		containerID := string(rune('a' + i))
+		if err := q.Enqueue(dataMessage(containerID)); err != nil {
+			t.Fatalf("Enqueue: %v", err)
+		}
+	}
+
+	depth, err := q.Depth()
+	if err != nil {
+		t.Fatalf("Depth: %v", err)
+	}
+	if depth != 3 {
+		t.Errorf("Depth() = %d, want 3 (bounded by maxDepth)", depth)
+	}
+
+	if len(dropped) != 2 {
+		t.Fatalf("dropped %d messages, want 2", len(dropped))
+	}
+	if dropped[0].ContainerID != "a" || dropped[1].ContainerID != "b" {
+		t.Errorf("dropped = %v, want the two oldest (a, b)", dropped)
+	}
+
+	messages, _, err := q.Peek(10)
+	if err != nil {
+		t.Fatalf("Peek: %v", err)
+	}
+	var remaining []string
+	for _, msg := range messages {
+		remaining = append(remaining, msg.ContainerID)
+	}
+	want := []string{"c", "d", "e"}
+	if len(remaining) != len(want) {
+		t.Fatalf("remaining = %v, want %v", remaining, want)
+	}
+	for i := range want {
+		if remaining[i] != want[i] {
+			t.Errorf("remaining = %v, want %v", remaining, want)
+		}
+	}
+}
+
+func TestEnqueueReportsDepthGauge(t *testing.T) {
+	var depths []int
+	q := openTestQueue(t, WithDepthGauge(func(depth int) {
+		depths = append(depths, depth)
+	}))
+
+	for i := 0; i < 3; i++ {
+		if err := q.Enqueue(dataMessage("c1")); err != nil {
+			t.Fatalf("Enqueue: %v", err)
+		}
+	}
+
+	want := []int{1, 2, 3}
+	if len(depths) != len(want) {
+		t.Fatalf("depths = %v, want %v", depths, want)
+	}
+	for i := range want {
+		if depths[i] != want[i] {
+			t.Errorf("depths = %v, want %v", depths, want)
+		}
+	}
+}
+
+func TestPeekAndRemove(t *testing.T) {
+	q := openTestQueue(t)
+
+	if err := q.Enqueue(dataMessage("c1")); err != nil {
+		t.Fatalf("Enqueue: %v", err)
+	}
+	if err := q.Enqueue(dataMessage("c2")); err != nil {
+		t.Fatalf("Enqueue: %v", err)
+	}
+
+	messages, keys, err := q.Peek(1)
+	if err != nil {
+		t.Fatalf("Peek: %v", err)
+	}
+	if len(messages) != 1 || messages[0].ContainerID != "c1" {
+		t.Fatalf("Peek(1) = %v, want [c1]", messages)
+	}
+
+	if err := q.Remove(keys); err != nil {
+		t.Fatalf("Remove: %v", err)
+	}
+
+	depth, err := q.Depth()
+	if err != nil {
+		t.Fatalf("Depth: %v", err)
+	}
+	if depth != 1 {
+		t.Errorf("Depth() = %d, want 1", depth)
+	}
+}
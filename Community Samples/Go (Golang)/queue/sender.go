@@ -0,0 +1,174 @@
+// Copyright 2018 OSIsoft, LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License")
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// <http://www.apache.org/licenses/LICENSE-2.0>
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package queue
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/osisoft/OMF-Samples/go/omf"
+)
+
+// Sender drains a Queue into an omf.Client, batching up to BatchSize
+// messages per Data request and backing off between attempts after a
+// failed send.
+type Sender struct {
+	Queue     *Queue
+	Client    *omf.Client
+	BatchSize int
+
+	// MinBackoff and MaxBackoff bound the delay between retries after a
+	// failed send; the delay doubles on each consecutive failure, reset
+	// to MinBackoff on the next success. A send that fails with a
+	// Retry-After header waits that long instead, regardless of bounds.
+	MinBackoff time.Duration
+	MaxBackoff time.Duration
+
+	// OnDepthChange, if set, is called after every Remove and drop with
+	// the queue's current depth, so a caller can mirror it into metrics
+	// without this package depending on a metrics library. Enqueue reports
+	// depth through the Queue's own WithDepthGauge option instead.
+	OnDepthChange func(depth int)
+
+	// OnDrop, if set, is called with a batch of messages the endpoint
+	// permanently rejected (see Temporary), once they've been removed
+	// from the queue.
+	OnDrop func(dropped []omf.DataMessage)
+
+	backoff time.Duration
+}
+
+// NewSender builds a Sender with backoff bounds of 1s and 1m.
+func NewSender(q *Queue, client *omf.Client, batchSize int) *Sender {
+	return &Sender{
+		Queue:      q,
+		Client:     client,
+		BatchSize:  batchSize,
+		MinBackoff: time.Second,
+		MaxBackoff: time.Minute,
+	}
+}
+
+// Run polls the queue every interval and drains it into s.Client until ctx
+// is canceled.
+func (s *Sender) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.Drain(ctx)
+		}
+	}
+}
+
+// Drain sends queued messages in batches of up to s.BatchSize until the
+// queue is empty, ctx is canceled, or a send fails temporarily (it then
+// waits out the backoff or Retry-After before returning). A batch the
+// endpoint permanently rejects is removed, passed to OnDrop, and draining
+// continues with the next batch. Run calls this on
+// every tick; a caller can also call it directly for a final flush during
+// shutdown, with its own deadline on ctx.
+func (s *Sender) Drain(ctx context.Context) {
+	for {
+		if ctx.Err() != nil {
+			return
+		}
+
+		messages, keys, err := s.Queue.Peek(s.BatchSize)
+		if err != nil || len(messages) == 0 {
+			return
+		}
+
+		if err := s.Client.Send(ctx, "create", messages); err != nil {
+			if isPermanent(err) {
+				if removeErr := s.Queue.Remove(keys); removeErr != nil {
+					return
+				}
+				s.backoff = 0
+				s.reportDepth()
+				if s.OnDrop != nil {
+					s.OnDrop(messages)
+				}
+				continue
+			}
+			s.wait(ctx, err)
+			return
+		}
+
+		if err := s.Queue.Remove(keys); err != nil {
+			return
+		}
+		s.backoff = 0
+		s.reportDepth()
+
+		if len(messages) < s.BatchSize {
+			return
+		}
+	}
+}
+
+// isPermanent reports whether sendErr is a rejection that retrying won't
+// fix (anything other than the endpoint being unreachable, rate-limiting,
+// or failing transiently), so the batch should be dropped instead of
+// wedging the queue behind it forever.
+func isPermanent(sendErr error) bool {
+	var omfErr *omf.SendError
+	return errors.As(sendErr, &omfErr) && !omfErr.Temporary()
+}
+
+// wait pauses before the next drain attempt: for a length given by
+// Retry-After, or an exponential backoff otherwise. It returns early if ctx
+// is canceled.
+func (s *Sender) wait(ctx context.Context, sendErr error) {
+	delay := s.nextBackoff()
+
+	var omfErr *omf.SendError
+	if errors.As(sendErr, &omfErr) && omfErr.RetryAfter > 0 {
+		delay = omfErr.RetryAfter
+	}
+
+	select {
+	case <-ctx.Done():
+	case <-time.After(delay):
+	}
+}
+
+// nextBackoff doubles s.backoff, starting from MinBackoff and capped at
+// MaxBackoff.
+func (s *Sender) nextBackoff() time.Duration {
+	if s.backoff == 0 {
+		s.backoff = s.MinBackoff
+	} else if s.backoff < s.MaxBackoff {
+		s.backoff *= 2
+		if s.backoff > s.MaxBackoff {
+			s.backoff = s.MaxBackoff
+		}
+	}
+	return s.backoff
+}
+
+func (s *Sender) reportDepth() {
+	if s.OnDepthChange == nil {
+		return
+	}
+	if depth, err := s.Queue.Depth(); err == nil {
+		s.OnDepthChange(depth)
+	}
+}
@@ -0,0 +1,210 @@
+// Copyright 2018 OSIsoft, LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License")
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// <http://www.apache.org/licenses/LICENSE-2.0>
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package queue
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/osisoft/OMF-Samples/go/endpoint"
+	"github.com/osisoft/OMF-Samples/go/omf"
+)
+
+// fakeEndpoint is the minimal endpoint.Endpoint needed to build an
+// omf.Client in tests, without depending on any real OMF product.
+type fakeEndpoint struct{}
+
+func (fakeEndpoint) Kind() endpoint.Kind         { return endpoint.Kind("fake") }
+func (fakeEndpoint) MessageURL() string          { return "http://fake.example/omf" }
+func (fakeEndpoint) SupportedVersions() []string { return []string{"1.2"} }
+func (fakeEndpoint) AuthHeaders(context.Context) (map[string]string, error) {
+	return nil, nil
+}
+func (fakeEndpoint) SuppressStaticMessages() bool { return false }
+
+// roundTripFunc adapts a function to http.RoundTripper, so tests can script
+// canned responses without a real server.
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) { return f(req) }
+
+func newStatusResponse(status int, header http.Header) *http.Response {
+	if header == nil {
+		header = http.Header{}
+	}
+	return &http.Response{
+		StatusCode: status,
+		Status:     http.StatusText(status),
+		Header:     header,
+		Body:       io.NopCloser(strings.NewReader("")),
+	}
+}
+
+func newTestSender(t *testing.T, responses []*http.Response) *Sender {
+	t.Helper()
+
+	call := 0
+	transport := roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		if call >= len(responses) {
+			t.Fatalf("unexpected extra Send call (only %d responses scripted)", len(responses))
+		}
+		resp := responses[call]
+		call++
+		return resp, nil
+	})
+
+	client := omf.NewClient(fakeEndpoint{}, &http.Client{Transport: transport}, "1.2")
+
+	q, err := Open(filepath.Join(t.TempDir(), "queue.db"))
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	t.Cleanup(func() { q.Close() })
+
+	s := NewSender(q, client, 10)
+	s.MinBackoff = time.Millisecond
+	s.MaxBackoff = 5 * time.Millisecond
+	return s
+}
+
+func TestIsPermanent(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"server error is temporary", &omf.SendError{StatusCode: 503, Err: errors.New("unavailable")}, false},
+		{"rate limited is temporary", &omf.SendError{StatusCode: http.StatusTooManyRequests, Err: errors.New("rate limited")}, false},
+		{"unreachable is temporary", &omf.SendError{StatusCode: 0, Err: errors.New("dial failed")}, false},
+		{"bad request is permanent", &omf.SendError{StatusCode: 400, Err: errors.New("bad schema")}, true},
+		{"not found is permanent", &omf.SendError{StatusCode: 404, Err: errors.New("not found")}, true},
+		{"non-SendError is not permanent", errors.New("some other error"), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isPermanent(tt.err); got != tt.want {
+				t.Errorf("isPermanent(%v) = %v, want %v", tt.err, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNextBackoffDoublesAndCaps(t *testing.T) {
+	s := &Sender{MinBackoff: time.Millisecond, MaxBackoff: 4 * time.Millisecond}
+
+	want := []time.Duration{time.Millisecond, 2 * time.Millisecond, 4 * time.Millisecond, 4 * time.Millisecond}
+	for i, w := range want {
+		if got := s.nextBackoff(); got != w {
+			t.Errorf("nextBackoff() call %d = %v, want %v", i, got, w)
+		}
+	}
+}
+
+func TestWaitHonorsRetryAfterOverBackoff(t *testing.T) {
+	s := &Sender{MinBackoff: time.Hour, MaxBackoff: time.Hour}
+	sendErr := &omf.SendError{RetryAfter: 5 * time.Millisecond, Err: errors.New("rate limited")}
+
+	start := time.Now()
+	s.wait(context.Background(), sendErr)
+	elapsed := time.Since(start)
+
+	if elapsed < 5*time.Millisecond || elapsed > time.Second {
+		t.Errorf("wait() took %v, want ~5ms (Retry-After), not the 1h backoff", elapsed)
+	}
+}
+
+func TestWaitReturnsEarlyOnContextCancel(t *testing.T) {
+	s := &Sender{MinBackoff: time.Hour, MaxBackoff: time.Hour}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	s.wait(ctx, errors.New("transient"))
+	elapsed := time.Since(start)
+
+	if elapsed > time.Second {
+		t.Errorf("wait() took %v, want it to return promptly once ctx is done", elapsed)
+	}
+}
+
+func TestDrainDropsPermanentlyRejectedBatch(t *testing.T) {
+	s := newTestSender(t, []*http.Response{newStatusResponse(400, nil)})
+
+	msg := omf.DataMessage{ContainerID: "c1", Values: []map[string]any{{"Temperature": 1}}}
+	if err := s.Queue.Enqueue(msg); err != nil {
+		t.Fatalf("Enqueue: %v", err)
+	}
+
+	var dropped []omf.DataMessage
+	s.OnDrop = func(batch []omf.DataMessage) { dropped = append(dropped, batch...) }
+
+	s.Drain(context.Background())
+
+	depth, err := s.Queue.Depth()
+	if err != nil {
+		t.Fatalf("Depth: %v", err)
+	}
+	if depth != 0 {
+		t.Errorf("Depth() = %d, want 0 (permanently rejected batch should be dropped)", depth)
+	}
+	if len(dropped) != 1 || dropped[0].ContainerID != "c1" {
+		t.Errorf("OnDrop saw %v, want the rejected message", dropped)
+	}
+}
+
+func TestDrainKeepsBatchOnTransientFailure(t *testing.T) {
+	s := newTestSender(t, []*http.Response{newStatusResponse(503, nil)})
+
+	msg := omf.DataMessage{ContainerID: "c1", Values: []map[string]any{{"Temperature": 1}}}
+	if err := s.Queue.Enqueue(msg); err != nil {
+		t.Fatalf("Enqueue: %v", err)
+	}
+
+	s.Drain(context.Background())
+
+	depth, err := s.Queue.Depth()
+	if err != nil {
+		t.Fatalf("Depth: %v", err)
+	}
+	if depth != 1 {
+		t.Errorf("Depth() = %d, want 1 (transient failure must not drop the batch)", depth)
+	}
+}
+
+func TestDrainRemovesBatchOnSuccess(t *testing.T) {
+	s := newTestSender(t, []*http.Response{newStatusResponse(200, nil)})
+
+	msg := omf.DataMessage{ContainerID: "c1", Values: []map[string]any{{"Temperature": 1}}}
+	if err := s.Queue.Enqueue(msg); err != nil {
+		t.Fatalf("Enqueue: %v", err)
+	}
+
+	s.Drain(context.Background())
+
+	depth, err := s.Queue.Depth()
+	if err != nil {
+		t.Fatalf("Depth: %v", err)
+	}
+	if depth != 0 {
+		t.Errorf("Depth() = %d, want 0 after a successful send", depth)
+	}
+}
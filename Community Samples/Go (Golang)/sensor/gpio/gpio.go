@@ -0,0 +1,78 @@
+// Copyright 2018 OSIsoft, LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License")
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// <http://www.apache.org/licenses/LICENSE-2.0>
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package gpio reads digital input pins on boards periph.io supports,
+// such as a Raspberry Pi. This replaces the GPIO.setmode/GPIO.input
+// comments that used to sit inert in the main sample.
+package gpio
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/osisoft/OMF-Samples/go/omf"
+	"periph.io/x/conn/v3/gpio"
+	"periph.io/x/conn/v3/gpio/gpioreg"
+	"periph.io/x/host/v3"
+)
+
+// Source reads the digital state of a fixed set of GPIO pins, named by
+// their periph.io pin name (e.g. "GPIO4", "GPIO5" on a Raspberry Pi).
+type Source struct {
+	pins map[string]gpio.PinIn
+}
+
+// New initializes the periph.io host drivers and opens pinNames as
+// digital inputs. Each pin is reported as an OMF reading named after its
+// pin name.
+func New(pinNames ...string) (*Source, error) {
+	if _, err := host.Init(); err != nil {
+		return nil, fmt.Errorf("gpio: initializing host drivers: %w", err)
+	}
+
+	pins := make(map[string]gpio.PinIn, len(pinNames))
+	for _, name := range pinNames {
+		pin := gpioreg.ByName(name)
+		if pin == nil {
+			return nil, fmt.Errorf("gpio: no such pin %q", name)
+		}
+		if err := pin.In(gpio.PullNoChange, gpio.NoEdge); err != nil {
+			return nil, fmt.Errorf("gpio: configuring pin %q as input: %w", name, err)
+		}
+		pins[name] = pin
+	}
+	return &Source{pins: pins}, nil
+}
+
+func (s *Source) Name() string { return "gpio" }
+
+func (s *Source) Schema() map[string]omf.Property {
+	schema := make(map[string]omf.Property, len(s.pins))
+	for name := range s.pins {
+		schema[name] = omf.Property{Type: "integer"}
+	}
+	return schema
+}
+
+func (s *Source) Read(context.Context) (map[string]any, error) {
+	values := make(map[string]any, len(s.pins))
+	for name, pin := range s.pins {
+		if pin.Read() == gpio.High {
+			values[name] = 1
+		} else {
+			values[name] = 0
+		}
+	}
+	return values, nil
+}
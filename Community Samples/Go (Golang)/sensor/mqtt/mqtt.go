@@ -0,0 +1,122 @@
+// Copyright 2018 OSIsoft, LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License")
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// <http://www.apache.org/licenses/LICENSE-2.0>
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package mqtt subscribes to an MQTT topic pattern (for example
+// "+/+/temperature", as used by the WSO2 IoT MQTT plugin) and maps each
+// message's last segment to an OMF container value.
+package mqtt
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+
+	paho "github.com/eclipse/paho.mqtt.golang"
+
+	"github.com/osisoft/OMF-Samples/go/omf"
+)
+
+// Source subscribes to TopicPattern on a broker and caches the most
+// recent numeric payload seen on each matching topic, keyed by the
+// topic's final segment (e.g. ".../temperature" -> "temperature").
+//
+// Fields must be declared up front (like the ngsi and gpio sources do),
+// rather than discovered from whichever messages happen to have arrived
+// by the time Schema is first called: the dynamic Type describing this
+// source's container is sent once, at startup, so Schema can't depend on
+// readings that arrive asynchronously on the MQTT client's own goroutine.
+type Source struct {
+	client paho.Client
+	fields []string
+
+	mu     sync.Mutex
+	values map[string]float64
+}
+
+// New connects to brokerURL and subscribes to topicPattern (an MQTT topic
+// filter such as "+/+/temperature") expecting to see the given fields as
+// final topic segments (e.g. "temperature"). Readings arrive
+// asynchronously on the client's own goroutine and are cached until the
+// next Read; a field never seen yet is simply omitted from Read's result.
+func New(brokerURL, topicPattern string, fields []string) (*Source, error) {
+	src := &Source{fields: fields, values: make(map[string]float64)}
+
+	opts := paho.NewClientOptions().AddBroker(brokerURL).SetClientID("omf-sample-mqtt-source")
+	opts.SetDefaultPublishHandler(src.onMessage)
+	src.client = paho.NewClient(opts)
+
+	if token := src.client.Connect(); token.Wait() && token.Error() != nil {
+		return nil, fmt.Errorf("mqtt: connecting to %s: %w", brokerURL, token.Error())
+	}
+	if token := src.client.Subscribe(topicPattern, 0, nil); token.Wait() && token.Error() != nil {
+		return nil, fmt.Errorf("mqtt: subscribing to %q: %w", topicPattern, token.Error())
+	}
+	return src, nil
+}
+
+func (s *Source) onMessage(_ paho.Client, msg paho.Message) {
+	reading, err := strconv.ParseFloat(strings.TrimSpace(string(msg.Payload())), 64)
+	if err != nil {
+		return
+	}
+	segments := strings.Split(msg.Topic(), "/")
+	field := segments[len(segments)-1]
+	if !s.isDeclaredField(field) {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.values[field] = reading
+}
+
+func (s *Source) isDeclaredField(field string) bool {
+	for _, f := range s.fields {
+		if f == field {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *Source) Name() string { return "mqtt" }
+
+// Schema returns the declared fields, not whatever has arrived so far, so
+// it's stable no matter when it's called relative to message arrival.
+func (s *Source) Schema() map[string]omf.Property {
+	schema := make(map[string]omf.Property, len(s.fields))
+	for _, field := range s.fields {
+		schema[field] = omf.Property{Type: "number"}
+	}
+	return schema
+}
+
+func (s *Source) Read(ctx context.Context) (map[string]any, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	values := make(map[string]any, len(s.values))
+	for field, reading := range s.values {
+		values[field] = reading
+	}
+	return values, nil
+}
+
+// Close disconnects from the broker, waiting up to 250ms for in-flight
+// messages to settle.
+func (s *Source) Close() {
+	s.client.Disconnect(250)
+}
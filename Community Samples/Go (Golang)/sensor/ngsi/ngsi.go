@@ -0,0 +1,99 @@
+// Copyright 2018 OSIsoft, LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License")
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// <http://www.apache.org/licenses/LICENSE-2.0>
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package ngsi polls a single entity's attributes from an NGSIv2/FIWARE
+// Context Broker and maps them into OMF readings.
+package ngsi
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/osisoft/OMF-Samples/go/omf"
+)
+
+// Source polls BrokerURL's /v2/entities/{EntityID} endpoint and reports
+// each of Attributes as a reading.
+type Source struct {
+	BrokerURL  string
+	EntityID   string
+	Attributes []string
+
+	httpClient *http.Client
+}
+
+// New builds a Source that polls brokerURL for entityID, reporting the
+// given attribute names as readings.
+func New(brokerURL, entityID string, attributes []string) *Source {
+	return &Source{
+		BrokerURL:  brokerURL,
+		EntityID:   entityID,
+		Attributes: attributes,
+		httpClient: http.DefaultClient,
+	}
+}
+
+func (s *Source) Name() string { return "ngsi" }
+
+func (s *Source) Schema() map[string]omf.Property {
+	schema := make(map[string]omf.Property, len(s.Attributes))
+	for _, attr := range s.Attributes {
+		schema[attr] = omf.Property{Type: "number"}
+	}
+	return schema
+}
+
+// ngsiAttribute is the NGSIv2 representation of one entity attribute:
+// {"value": ..., "type": ..., "metadata": {...}}.
+type ngsiAttribute struct {
+	Value float64 `json:"value"`
+}
+
+func (s *Source) Read(ctx context.Context) (map[string]any, error) {
+	url := fmt.Sprintf("%s/v2/entities/%s", s.BrokerURL, s.EntityID)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("ngsi: polling entity %q: %w", s.EntityID, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("ngsi: entity %q returned %s", s.EntityID, resp.Status)
+	}
+
+	var entity map[string]json.RawMessage
+	if err := json.NewDecoder(resp.Body).Decode(&entity); err != nil {
+		return nil, fmt.Errorf("ngsi: decoding entity %q: %w", s.EntityID, err)
+	}
+
+	values := make(map[string]any, len(s.Attributes))
+	for _, attr := range s.Attributes {
+		raw, ok := entity[attr]
+		if !ok {
+			continue
+		}
+		var parsed ngsiAttribute
+		if err := json.Unmarshal(raw, &parsed); err != nil {
+			return nil, fmt.Errorf("ngsi: parsing attribute %q: %w", attr, err)
+		}
+		values[attr] = parsed.Value
+	}
+	return values, nil
+}
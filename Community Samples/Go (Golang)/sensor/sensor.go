@@ -0,0 +1,93 @@
+// Copyright 2018 OSIsoft, LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License")
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// <http://www.apache.org/licenses/LICENSE-2.0>
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package sensor defines the pluggable edge-gateway Source interface that
+// this sample reads live readings from, plus a Simulated source that
+// preserves the original random-value demo behavior. Concrete hardware
+// and protocol sources live in the gpio, mqtt, and ngsi subpackages.
+package sensor
+
+import (
+	"context"
+
+	"github.com/osisoft/OMF-Samples/go/omf"
+)
+
+// Source is one origin of live readings: a GPIO bus, an MQTT topic
+// subscription, an NGSI Context Broker poll, or (by default) the built-in
+// Simulated source. Registering several Sources lets the sample act as a
+// real edge gateway that forwards readings from more than one place.
+type Source interface {
+	// Name identifies this source in logs and errors.
+	Name() string
+
+	// Schema describes the OMF properties this source contributes to the
+	// dynamic DataValuesType; keys match the field names Read returns.
+	Schema() map[string]omf.Property
+
+	// Read returns the latest values for this source's fields. It is
+	// called once per interval from the main loop.
+	Read(ctx context.Context) (map[string]any, error)
+}
+
+// Registry holds the set of Sources the main loop reads from each
+// interval.
+type Registry struct {
+	sources []Source
+}
+
+// NewRegistry builds a Registry from the given sources.
+func NewRegistry(sources ...Source) *Registry {
+	return &Registry{sources: sources}
+}
+
+// Schema merges every registered Source's Schema into one map, suitable
+// for omf.NewDynamicType plus the "Time" index property the caller adds.
+func (r *Registry) Schema() map[string]omf.Property {
+	merged := make(map[string]omf.Property)
+	for _, src := range r.sources {
+		for name, prop := range src.Schema() {
+			merged[name] = prop
+		}
+	}
+	return merged
+}
+
+// Read polls every registered Source and merges their values into one
+// map. A Source that fails to read is skipped, and its error is returned
+// wrapped with its Name so the caller can log it and continue with the
+// sources that did succeed.
+func (r *Registry) Read(ctx context.Context) (map[string]any, []error) {
+	values := make(map[string]any)
+	var errs []error
+	for _, src := range r.sources {
+		reading, err := src.Read(ctx)
+		if err != nil {
+			errs = append(errs, &readError{source: src.Name(), err: err})
+			continue
+		}
+		for name, value := range reading {
+			values[name] = value
+		}
+	}
+	return values, errs
+}
+
+type readError struct {
+	source string
+	err    error
+}
+
+func (e *readError) Error() string { return e.source + ": " + e.err.Error() }
+func (e *readError) Unwrap() error { return e.err }
@@ -0,0 +1,44 @@
+// Copyright 2018 OSIsoft, LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License")
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// <http://www.apache.org/licenses/LICENSE-2.0>
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sensor
+
+import (
+	"context"
+	"math/rand"
+
+	"github.com/osisoft/OMF-Samples/go/omf"
+)
+
+// Simulated is the default Source when no real hardware or protocol
+// source is configured: it fabricates two random readings, exactly like
+// this sample always has, so the sample still runs out of the box on a
+// machine with no sensors attached.
+type Simulated struct{}
+
+func (Simulated) Name() string { return "simulated" }
+
+func (Simulated) Schema() map[string]omf.Property {
+	return map[string]omf.Property{
+		"Raw Sensor Reading 1": {Type: "number"},
+		"Raw Sensor Reading 2": {Type: "number"},
+	}
+}
+
+func (Simulated) Read(context.Context) (map[string]any, error) {
+	return map[string]any{
+		"Raw Sensor Reading 1": 100 * rand.Float64(),
+		"Raw Sensor Reading 2": 100 * rand.Float64(),
+	}, nil
+}